@@ -1,7 +1,13 @@
 package amqp
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -10,9 +16,19 @@ var (
 	// DefaultURL is the default amqp url to connect to.
 	DefaultURL = "amqp://localhost"
 
-	// DefaultOnDisconnect is the default callback for when AMQP gets disconnected.
-	DefaultOnDisconnect = func() {
-		panic("Lost connection")
+	// DefaultOnDisconnect is the default callback for when AMQP gets
+	// disconnected. It's a no-op; the Exchange will automatically attempt to
+	// reconnect using its ReconnectOptions. Set ExchangeOptions.OnDisconnect
+	// if you want to observe disconnects.
+	DefaultOnDisconnect = func() {}
+
+	// DefaultReconnectOptions are the default backoff settings used when
+	// ExchangeOptions.Reconnect is nil.
+	DefaultReconnectOptions = &ReconnectOptions{
+		InitialInterval: time.Second,
+		MaxInterval:     30 * time.Second,
+		MaxRetries:      0,
+		Jitter:          0.2,
 	}
 
 	// DefaultExchangeOptions are the default options used when building a new Exchange.
@@ -31,6 +47,15 @@ var (
 		RoutingKey: "",
 	}
 
+	// ErrConfirmsDisabled is returned by PublishWithConfirm if
+	// ExchangeOptions.Confirms was not enabled on the Exchange.
+	ErrConfirmsDisabled = errors.New("amqp: Confirms must be enabled in ExchangeOptions to use PublishWithConfirm")
+
+	// errConfirmAbandoned is delivered to any in-flight PublishWithConfirm
+	// callers when a reconnect replaces the channel before their confirmation
+	// arrives.
+	errConfirmAbandoned = errors.New("amqp: channel closed before publish was confirmed")
+
 	ErrClosed          = amqp.ErrClosed
 	ErrSASL            = amqp.ErrSASL
 	ErrCredentials     = amqp.ErrCredentials
@@ -42,15 +67,114 @@ var (
 	ErrFieldType       = amqp.ErrFieldType
 )
 
+// ReconnectOptions configures the backoff used to re-dial the broker after
+// the connection is unexpectedly lost.
+type ReconnectOptions struct {
+	// InitialInterval is the backoff duration before the first reconnect
+	// attempt. Subsequent attempts double, up to MaxInterval.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the backoff duration between reconnect attempts.
+	MaxInterval time.Duration
+
+	// MaxRetries is the maximum number of reconnect attempts before giving
+	// up. A value of 0 means retry forever.
+	MaxRetries int
+
+	// Jitter is the fraction (0-1) of each backoff interval to randomize, so
+	// that multiple clients don't all reconnect at the same instant.
+	Jitter float64
+}
+
 // ExchangeOptions can be passed to NewExchange to configure the Exchange.
-// If the connection is lost then OnDisconnect is called. OnDisconnect returns whether or not to
-// continue processing.
+// If the connection is lost then OnDisconnect is called, and the Exchange
+// will attempt to reconnect according to Reconnect.
 type ExchangeOptions struct {
 	Name         string
 	Type         string
 	Durable      bool
 	AutoDelete   bool
 	OnDisconnect func()
+
+	// OnReconnect, if set, is called after the connection, channel and all
+	// previously declared queues, bindings and consumers have been
+	// successfully re-established.
+	OnReconnect func()
+
+	// Reconnect configures the backoff used when reconnecting. If nil,
+	// DefaultReconnectOptions is used.
+	Reconnect *ReconnectOptions
+
+	// DisableReconnect disables automatic reconnection. When the connection
+	// is lost, OnDisconnect is called and the Exchange is left closed.
+	DisableReconnect bool
+
+	// Confirms puts the channel into confirm mode, required to use
+	// PublishWithConfirm.
+	Confirms bool
+
+	// OnReturn, if set, is called for every message returned by the broker
+	// because it was published as Mandatory (or Immediate) and could not be
+	// routed to any queue.
+	OnReturn func(Return)
+
+	// Codec is used by PublishValue and Queue.SubscribeTyped to marshal and
+	// unmarshal message bodies. If nil, DefaultCodec (JSON) is used.
+	Codec Codec
+
+	// URLs, if set, takes precedence over the url passed to NewExchange.
+	// Each (re)connect attempt tries the next URL in round-robin order,
+	// failing over across a RabbitMQ cluster.
+	URLs []string
+
+	// Dialer configures TLS, SASL, heartbeat and other low-level connection
+	// settings. If nil, amqp.Dial's defaults are used. The vhost is taken
+	// from each URL, same as amqp.Dial.
+	Dialer *Dialer
+}
+
+// PubOpt configures an individual Publish, PublishTransient or
+// PublishWithConfirm call.
+type PubOpt func(*pubOptions)
+
+type pubOptions struct {
+	mandatory bool
+	immediate bool
+}
+
+// Mandatory marks a publish as mandatory: the broker returns the message
+// (observed via ExchangeOptions.OnReturn) instead of silently dropping it if
+// it can't be routed to any queue.
+func Mandatory() PubOpt {
+	return func(o *pubOptions) { o.mandatory = true }
+}
+
+// Immediate marks a publish as immediate: the broker returns the message if
+// it can't be delivered to a consumer immediately.
+func Immediate() PubOpt {
+	return func(o *pubOptions) { o.immediate = true }
+}
+
+// Return represents a message that the broker could not route and handed
+// back to the publisher because it was published as Mandatory or Immediate.
+type Return struct {
+	ReplyCode  uint16
+	ReplyText  string
+	RoutingKey string
+	Body       []byte
+}
+
+// Confirmation represents the broker's acknowledgement of a message
+// published with PublishWithConfirm.
+type Confirmation struct {
+	// Ack is true if the broker accepted responsibility for the message,
+	// false if it was nacked.
+	Ack bool
+}
+
+type confirmResult struct {
+	confirmation Confirmation
+	err          error
 }
 
 // QueueOptions can be passed to NewQueue to configure the queue.
@@ -60,112 +184,522 @@ type QueueOptions struct {
 	RoutingKey    string
 	PrefetchCount int
 	PrefetchSize  int
+
+	// DeadLetterExchange, if set, is declared as the queue's
+	// x-dead-letter-exchange argument: messages that are nacked without
+	// requeue, or that expire, are republished there.
+	DeadLetterExchange string
+
+	// DeadLetterRoutingKey, if set, is declared as the queue's
+	// x-dead-letter-routing-key argument.
+	DeadLetterRoutingKey string
+
+	// MessageTTL, if set, is declared as the queue's x-message-ttl
+	// argument.
+	MessageTTL time.Duration
+
+	// MaxRetries is the number of times Acknowledger.Retry will republish a
+	// message to the next retry bucket before dead-lettering it to
+	// DeadLetterExchange. Requires RetryBackoff.
+	MaxRetries int
+
+	// RetryBackoff declares one companion retry queue per entry. Retry
+	// republishes a message to the bucket matching its retry count; each
+	// bucket holds the message for its backoff duration before
+	// dead-lettering it back onto the main queue via x-dead-letter-exchange.
+	// Requires DeadLetterExchange to be set.
+	RetryBackoff []time.Duration
+
+	// RequeueOnError controls whether SubscribeHandler requeues a message
+	// when its handler returns an error. Defaults to false (nack without
+	// requeue).
+	RequeueOnError bool
+
+	// ShutdownTimeout bounds how long Consumer.Close, returned by
+	// SubscribeHandler, waits for in-flight handlers to finish before
+	// giving up. Defaults to 30 seconds.
+	ShutdownTimeout time.Duration
 }
 
 // Exchange represents an amqp exchange and wraps an amqp.Connection
 // and an amqp.Channel.
 type Exchange struct {
-	Name         string
-	connection   *amqp.Connection
-	channel      *amqp.Channel
+	Name string
+
+	urls     []string
+	urlIndex int
+	options  *ExchangeOptions
+
 	onDisconnect func()
+	onReconnect  func()
+	codec        Codec
+
+	mu         sync.Mutex
+	connection *amqp.Connection
+	channel    *amqp.Channel
+	closed     bool
+	queues     []*Queue
+
+	confirmsMu sync.Mutex
+	nextTag    uint64
+	pending    map[uint64]chan confirmResult
 }
 
 // NewExchange connects to rabbitmq, opens a channel and returns a new
 // Exchange instance. If url is an empty string, it will attempt to connect
-// to localhost.
+// to localhost. If options.URLs is set, it's used instead of url, and each
+// (re)connect attempt round-robins across it.
+//
+// If the connection is lost, the Exchange automatically redials, reopens a
+// channel and re-declares the exchange, any Queues created with it, and
+// restarts any active Subscribe consumers. See ExchangeOptions.Reconnect to
+// configure this behavior, and ExchangeOptions.DisableReconnect to disable it.
 func NewExchange(url string, options *ExchangeOptions) (*Exchange, error) {
-	if url == "" {
-		url = DefaultURL
-	}
-
 	if options == nil {
 		options = DefaultExchangeOptions
 	}
 
-	c, err := amqp.Dial(url)
-	if err != nil {
+	if options.OnDisconnect == nil {
+		options.OnDisconnect = DefaultOnDisconnect
+	}
+
+	if options.Codec == nil {
+		options.Codec = DefaultCodec
+	}
+
+	urls := options.URLs
+	if len(urls) == 0 {
+		if url == "" {
+			url = DefaultURL
+		}
+		urls = []string{url}
+	}
+
+	e := &Exchange{
+		Name:         options.Name,
+		urls:         urls,
+		options:      options,
+		onDisconnect: options.OnDisconnect,
+		onReconnect:  options.OnReconnect,
+		codec:        options.Codec,
+	}
+
+	if err := e.connect(); err != nil {
 		return nil, err
 	}
 
+	if !options.DisableReconnect {
+		go e.watch()
+	}
+
+	return e, nil
+}
+
+// connect dials the broker, opens a channel and declares the exchange. It's
+// used both for the initial connection and when reconnecting.
+func (e *Exchange) connect() error {
+	addr := e.nextURL()
+
+	var c *amqp.Connection
+	var err error
+	if e.options.Dialer != nil {
+		c, err = amqp.DialConfig(addr, e.options.Dialer.config())
+	} else {
+		c, err = amqp.Dial(addr)
+	}
+	if err != nil {
+		return err
+	}
+
 	ch, err := c.Channel()
 	if err != nil {
-		return nil, err
+		c.Close()
+		return err
 	}
 
-	if options.OnDisconnect == nil {
-		options.OnDisconnect = DefaultOnDisconnect
+	if err := ch.ExchangeDeclare(
+		e.options.Name,       // name
+		e.options.Type,       // kind
+		e.options.Durable,    // durable
+		e.options.AutoDelete, // autoDelete
+		false,                // internal
+		false,                // noWait
+		nil,                  // args
+	); err != nil {
+		ch.Close()
+		c.Close()
+		return err
 	}
 
-	err = ch.ExchangeDeclare(
-		options.Name,       // name
-		options.Type,       // kind
-		options.Durable,    // durable
-		options.AutoDelete, // autoDelete
-		false,              // internal
-		false,              // noWait
-		nil,                // args
-	)
-	if err != nil {
-		return nil, err
+	if e.options.Confirms {
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			c.Close()
+			return err
+		}
 	}
 
-	return &Exchange{
-		Name:         options.Name,
-		connection:   c,
-		channel:      ch,
-		onDisconnect: options.OnDisconnect,
-	}, nil
+	e.mu.Lock()
+	oldConnection, oldChannel := e.connection, e.channel
+	e.connection = c
+	e.channel = ch
+	e.mu.Unlock()
+
+	// Close the previous generation's connection/channel now that it's been
+	// replaced. On a reconnect where replay subsequently fails, this is what
+	// stops the next connect() attempt from leaking the one we just opened.
+	if oldChannel != nil {
+		oldChannel.Close()
+	}
+	if oldConnection != nil {
+		oldConnection.Close()
+	}
+
+	e.resetConfirms()
+
+	if e.options.Confirms {
+		go e.watchConfirms(ch.NotifyPublish(make(chan amqp.Confirmation, 1)))
+	}
+
+	if e.options.OnReturn != nil {
+		go e.watchReturns(ch.NotifyReturn(make(chan amqp.Return, 1)))
+	}
+
+	return nil
+}
+
+// resetConfirms abandons any publishes still awaiting confirmation from a
+// previous channel generation, and resets the delivery tag sequence for the
+// new one.
+func (e *Exchange) resetConfirms() {
+	e.confirmsMu.Lock()
+	defer e.confirmsMu.Unlock()
+
+	for tag, waiter := range e.pending {
+		waiter <- confirmResult{err: errConfirmAbandoned}
+		delete(e.pending, tag)
+	}
+
+	e.nextTag = 0
+	e.pending = make(map[uint64]chan confirmResult)
+}
+
+// watchConfirms delivers broker acks/nacks to their matching
+// PublishWithConfirm caller.
+func (e *Exchange) watchConfirms(confirms <-chan amqp.Confirmation) {
+	for c := range confirms {
+		e.confirmsMu.Lock()
+		waiter, ok := e.pending[c.DeliveryTag]
+		delete(e.pending, c.DeliveryTag)
+		e.confirmsMu.Unlock()
+
+		if ok {
+			waiter <- confirmResult{confirmation: Confirmation{Ack: c.Ack}}
+		}
+	}
+}
+
+// watchReturns forwards messages the broker couldn't route to
+// ExchangeOptions.OnReturn.
+func (e *Exchange) watchReturns(returns <-chan amqp.Return) {
+	for r := range returns {
+		e.options.OnReturn(Return{
+			ReplyCode:  r.ReplyCode,
+			ReplyText:  r.ReplyText,
+			RoutingKey: r.RoutingKey,
+			Body:       r.Body,
+		})
+	}
+}
+
+// watch monitors the connection for unexpected closure and triggers a
+// reconnect. It returns once the Exchange is explicitly closed, or once
+// reconnection is given up on. NewExchange does not start it when
+// DisableReconnect is set.
+func (e *Exchange) watch() {
+	for {
+		e.mu.Lock()
+		c := e.connection
+		e.mu.Unlock()
+
+		err, ok := <-c.NotifyClose(make(chan *amqp.Error, 1))
+
+		e.mu.Lock()
+		closed := e.closed
+		e.mu.Unlock()
+		if closed || !ok || err == nil {
+			// Closed intentionally via Exchange.Close.
+			return
+		}
+
+		e.onDisconnect()
+
+		if !e.reconnect() {
+			return
+		}
+
+		if e.onReconnect != nil {
+			e.onReconnect()
+		}
+	}
+}
+
+// reconnect redials the broker with backoff until it succeeds, the Exchange
+// is closed, or MaxRetries is exceeded.
+func (e *Exchange) reconnect() bool {
+	opts := e.options.Reconnect
+	if opts == nil {
+		opts = DefaultReconnectOptions
+	}
+
+	interval := opts.InitialInterval
+	if interval <= 0 {
+		interval = DefaultReconnectOptions.InitialInterval
+	}
+
+	for attempt := 1; ; attempt++ {
+		e.mu.Lock()
+		closed := e.closed
+		e.mu.Unlock()
+		if closed {
+			return false
+		}
+
+		time.Sleep(jitter(interval, opts.Jitter))
+
+		if err := e.connect(); err == nil {
+			if err := e.replay(); err == nil {
+				return true
+			}
+		}
+
+		if opts.MaxRetries > 0 && attempt >= opts.MaxRetries {
+			return false
+		}
+
+		interval = nextInterval(interval, opts.MaxInterval)
+	}
+}
+
+// replay re-declares every Queue (and its bindings, QoS and active
+// consumers) that was created against this Exchange.
+func (e *Exchange) replay() error {
+	e.mu.Lock()
+	queues := make([]*Queue, len(e.queues))
+	copy(queues, e.queues)
+	e.mu.Unlock()
+
+	for _, q := range queues {
+		if err := q.redeclare(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func nextInterval(current, max time.Duration) time.Duration {
+	next := current * 2
+	if max > 0 && next > max {
+		next = max
+	}
+	return next
+}
+
+// jitter randomizes interval by +/- fraction.
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return interval
+	}
+
+	delta := time.Duration(float64(interval) * fraction)
+	if delta <= 0 {
+		return interval
+	}
+
+	return interval - delta + time.Duration(rand.Int63n(int64(delta)*2+1))
+}
+
+// nextURL returns the next URL to dial, round-robining across e.urls. It's
+// only ever called from connect, which is never run concurrently with
+// itself.
+func (e *Exchange) nextURL() string {
+	addr := e.urls[e.urlIndex%len(e.urls)]
+	e.urlIndex++
+	return addr
+}
+
+// channelRef returns the current amqp.Channel, safe for use across reconnects.
+func (e *Exchange) channelRef() *amqp.Channel {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.channel
 }
 
 // Publish publishes a persistent message to the Exchange.
-func (e *Exchange) Publish(routingKey, message, requestID string) error {
-	return e.publish(routingKey, message, requestID, amqp.Persistent)
+func (e *Exchange) Publish(routingKey, message, requestID string, opts ...PubOpt) error {
+	return e.publish(routingKey, message, requestID, amqp.Persistent, opts...)
 }
 
 // PublishTransient publishes a transient message to the Exchange.
-func (e *Exchange) PublishTransient(routingKey, message, requestID string) error {
-	return e.publish(routingKey, message, requestID, amqp.Transient)
+func (e *Exchange) PublishTransient(routingKey, message, requestID string, opts ...PubOpt) error {
+	return e.publish(routingKey, message, requestID, amqp.Transient, opts...)
+}
+
+// PublishValue marshals v using the Exchange's Codec (JSON by default, see
+// ExchangeOptions.Codec) and publishes it as a persistent message.
+func (e *Exchange) PublishValue(routingKey string, v interface{}, requestID string, opts ...PubOpt) error {
+	body, contentType, err := e.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return e.publishBody(routingKey, body, contentType, requestID, amqp.Persistent, opts...)
 }
 
-func (e *Exchange) publish(routingKey, message, requestID string, deliveryMode uint8) error {
-	if e.channel == nil {
+// PublishWithConfirm publishes a persistent message to the Exchange in
+// confirm mode and blocks until the broker acks or nacks it, or ctx is done.
+// ExchangeOptions.Confirms must be true.
+func (e *Exchange) PublishWithConfirm(ctx context.Context, routingKey, message, requestID string, opts ...PubOpt) (*Confirmation, error) {
+	if !e.options.Confirms {
+		return nil, ErrConfirmsDisabled
+	}
+
+	ch := e.channelRef()
+	if ch == nil {
+		return nil, errors.New("channel is nil")
+	}
+
+	o := pubOptionsFrom(opts)
+	waiter := make(chan confirmResult, 1)
+	msg := newPublishing([]byte(message), "application/json", requestID, amqp.Persistent)
+
+	if err := e.doPublish(ch, e.Name, routingKey, msg, o.mandatory, o.immediate, waiter); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-waiter:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return &r.confirmation, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *Exchange) publish(routingKey, message, requestID string, deliveryMode uint8, opts ...PubOpt) error {
+	return e.publishBody(routingKey, []byte(message), "application/json", requestID, deliveryMode, opts...)
+}
+
+func (e *Exchange) publishBody(routingKey string, body []byte, contentType, requestID string, deliveryMode uint8, opts ...PubOpt) error {
+	ch := e.channelRef()
+	if ch == nil {
 		return errors.New("channel is nil")
 	}
 
-	msg := amqp.Publishing{
+	o := pubOptionsFrom(opts)
+	msg := newPublishing(body, contentType, requestID, deliveryMode)
+
+	return e.doPublish(ch, e.Name, routingKey, msg, o.mandatory, o.immediate, nil)
+}
+
+// doPublish publishes msg to exchangeName on ch, allocating (and, if waiter
+// is non-nil, registering) the confirm delivery tag under the same
+// confirmsMu critical section as the Publish call itself. This keeps the
+// locally tracked tag sequence in lockstep with the broker's, even under
+// concurrent publishers: without it, two goroutines could acquire tags in
+// one order but reach ch.Publish in the other, misattributing confirmations.
+// Every publish that can occur on the channel must go through here while
+// Confirms is enabled, including the dead-letter/retry republish in
+// acknowledger.Retry, or the local sequence silently desyncs from the
+// broker's.
+func (e *Exchange) doPublish(ch *amqp.Channel, exchangeName, routingKey string, msg amqp.Publishing, mandatory, immediate bool, waiter chan confirmResult) error {
+	if !e.options.Confirms {
+		return ch.Publish(exchangeName, routingKey, mandatory, immediate, msg)
+	}
+
+	e.confirmsMu.Lock()
+	defer e.confirmsMu.Unlock()
+
+	tag := e.allocateTag(waiter)
+
+	if err := ch.Publish(exchangeName, routingKey, mandatory, immediate, msg); err != nil {
+		if waiter != nil {
+			delete(e.pending, tag)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// allocateTag assigns the next delivery tag and, if waiter is non-nil,
+// registers it in e.pending. Callers must hold confirmsMu across both this
+// call and the Publish call it corresponds to, or the locally tracked
+// sequence can end up out of order with the tags the broker actually
+// assigns.
+func (e *Exchange) allocateTag(waiter chan confirmResult) uint64 {
+	e.nextTag++
+	tag := e.nextTag
+	if waiter != nil {
+		e.pending[tag] = waiter
+	}
+	return tag
+}
+
+func newPublishing(body []byte, contentType, requestID string, deliveryMode uint8) amqp.Publishing {
+	return amqp.Publishing{
 		Headers: amqp.Table{
 			"request_id": requestID,
 		},
-		ContentType:  "application/json",
-		Body:         []byte(message),
+		ContentType:  contentType,
+		Body:         body,
 		DeliveryMode: deliveryMode,
 		Priority:     0,
 	}
+}
 
-	return e.channel.Publish(
-		e.Name,     // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // imediate
-		msg,        // message
-	)
+func pubOptionsFrom(opts []PubOpt) pubOptions {
+	var o pubOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
 // Close closes the connection.
 func (e *Exchange) Close() error {
-	if err := e.channel.Close(); err != nil {
+	e.mu.Lock()
+	e.closed = true
+	ch := e.channel
+	conn := e.connection
+	e.mu.Unlock()
+
+	if err := ch.Close(); err != nil {
 		return err
 	}
 
-	return e.connection.Close()
+	return conn.Close()
+}
+
+// Closed returns whether the Exchange has been explicitly closed via Close.
+func (e *Exchange) Closed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.closed
 }
 
 // Queue represents an amqp queue.
 type Queue struct {
 	exchange   *Exchange
+	options    *QueueOptions
 	routingKey string
 	name       string
+
+	mu        sync.Mutex
+	consumers []chan<- *Message
 }
 
 // NewQueue returns a new Queue instance.
@@ -174,35 +708,141 @@ func NewQueue(queueName string, exchange *Exchange, options *QueueOptions) (*Que
 		options = DefaultQueueOptions
 	}
 
-	_, err := exchange.channel.QueueDeclare(
-		queueName,          // name
-		options.Durable,    // durable
-		options.AutoDelete, // autoDelete
-		false,              // exclusive
-		false,              // noWait
-		nil,                // args
+	q := &Queue{
+		exchange:   exchange,
+		options:    options,
+		routingKey: options.RoutingKey,
+		name:       queueName,
+	}
+
+	if err := q.declare(); err != nil {
+		return nil, err
+	}
+
+	exchange.mu.Lock()
+	exchange.queues = append(exchange.queues, q)
+	exchange.mu.Unlock()
+
+	return q, nil
+}
+
+// declare declares the queue, its dead-letter/retry arguments and QoS
+// settings. It's called on initial creation and replayed after the Exchange
+// reconnects.
+func (q *Queue) declare() error {
+	if len(q.options.RetryBackoff) > 0 && q.options.DeadLetterExchange == "" {
+		return errors.New("amqp: QueueOptions.RetryBackoff requires DeadLetterExchange to be set")
+	}
+
+	ch := q.exchange.channelRef()
+
+	_, err := ch.QueueDeclare(
+		q.name,               // name
+		q.options.Durable,    // durable
+		q.options.AutoDelete, // autoDelete
+		false,                // exclusive
+		false,                // noWait
+		q.declareArgs(),      // args
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if options.PrefetchCount > 0 || options.PrefetchSize > 0 {
-		err = exchange.channel.Qos(options.PrefetchCount, options.PrefetchSize, false)
-		if err != nil {
-			return nil, err
+	if q.options.PrefetchCount > 0 || q.options.PrefetchSize > 0 {
+		if err := ch.Qos(q.options.PrefetchCount, q.options.PrefetchSize, false); err != nil {
+			return err
 		}
 	}
 
-	return &Queue{
-		exchange:   exchange,
-		routingKey: options.RoutingKey,
-		name:       queueName,
-	}, nil
+	for bucket, backoff := range q.options.RetryBackoff {
+		if err := q.declareRetryQueue(bucket, backoff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// declareArgs builds the x-dead-letter-exchange/x-dead-letter-routing-key/
+// x-message-ttl arguments for the main queue, if any are configured.
+func (q *Queue) declareArgs() amqp.Table {
+	args := amqp.Table{}
+
+	if q.options.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = q.options.DeadLetterExchange
+	}
+
+	if q.options.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = q.options.DeadLetterRoutingKey
+	}
+
+	if q.options.MessageTTL > 0 {
+		args["x-message-ttl"] = int64(q.options.MessageTTL / time.Millisecond)
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	return args
+}
+
+// declareRetryQueue declares the companion retry queue for a backoff bucket.
+// Messages published there sit for backoff, then dead-letter back onto this
+// Exchange with the queue's routing key, redelivering them to the main
+// queue.
+func (q *Queue) declareRetryQueue(bucket int, backoff time.Duration) error {
+	ch := q.exchange.channelRef()
+
+	_, err := ch.QueueDeclare(
+		q.retryQueueName(bucket),
+		q.options.Durable,
+		q.options.AutoDelete,
+		false,
+		false,
+		amqp.Table{
+			"x-dead-letter-exchange":    q.exchange.Name,
+			"x-dead-letter-routing-key": q.routingKey,
+			"x-message-ttl":             int64(backoff / time.Millisecond),
+		},
+	)
+
+	return err
+}
+
+// retryQueueName returns the name of the companion retry queue for bucket.
+func (q *Queue) retryQueueName(bucket int) string {
+	return fmt.Sprintf("%s.retry.%d", q.name, bucket)
+}
+
+// redeclare re-declares the queue, its bindings and QoS, and restarts any
+// active consumers. It's called after the Exchange reconnects.
+func (q *Queue) redeclare() error {
+	if err := q.declare(); err != nil {
+		return err
+	}
+
+	if err := q.bind(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	consumers := make([]chan<- *Message, len(q.consumers))
+	copy(consumers, q.consumers)
+	q.mu.Unlock()
+
+	for _, messages := range consumers {
+		if err := q.consume(messages); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // Purge purges all messages in the queue.
 func (q *Queue) Purge() error {
-	_, err := q.exchange.channel.QueuePurge(q.name, false)
+	_, err := q.exchange.channelRef().QueuePurge(q.name, false)
 	return err
 }
 
@@ -211,13 +851,152 @@ func (q *Queue) Name() string {
 	return q.name
 }
 
-// Subscribe starts consuming from the queue.
+// Subscribe starts consuming from the queue, feeding deliveries into
+// messages. If the Exchange's connection is lost and reconnected, the
+// consumer is automatically restarted against the new channel and continues
+// feeding the same messages channel.
 func (q *Queue) Subscribe(messages chan<- *Message) error {
 	if err := q.bind(); err != nil {
 		return err
 	}
 
-	dd, err := q.exchange.channel.Consume(
+	q.mu.Lock()
+	q.consumers = append(q.consumers, messages)
+	q.mu.Unlock()
+
+	return q.consume(messages)
+}
+
+// SubscribeTyped starts consuming from the queue, using the Exchange's Codec
+// to unmarshal each delivery's body into a new value of the same type as
+// prototype before invoking handler. The message is acked if handler returns
+// nil, and nacked (without requeue) if unmarshaling or handler fails.
+func (q *Queue) SubscribeTyped(prototype interface{}, handler func(ctx context.Context, v interface{}, ack Acknowledger) error) error {
+	messages := make(chan *Message)
+	if err := q.Subscribe(messages); err != nil {
+		return err
+	}
+
+	t := reflect.TypeOf(prototype)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	go func() {
+		for m := range messages {
+			v := reflect.New(t).Interface()
+
+			if err := q.exchange.codec.Unmarshal(m.Body, v); err != nil {
+				m.Nack(false)
+				continue
+			}
+
+			if err := handler(context.Background(), v, m.Acknowledger); err != nil {
+				m.Nack(false)
+				continue
+			}
+
+			m.Ack()
+		}
+	}()
+
+	return nil
+}
+
+// Consumer is a running SubscribeHandler worker pool.
+type Consumer struct {
+	queue    *Queue
+	cancel   context.CancelFunc
+	inFlight *sync.WaitGroup
+}
+
+// Close cancels the per-message context shared by every handler invocation,
+// then stops the consumer from receiving further deliveries, then waits for
+// handlers already in flight to finish, up to QueueOptions.ShutdownTimeout
+// (default 30s) or until ctx is done, whichever comes first. Canceling the
+// context first gives in-flight handlers the earliest possible signal to
+// exit within the wait window.
+func (c *Consumer) Close(ctx context.Context) error {
+	c.cancel()
+
+	err := c.queue.exchange.channelRef().Cancel(c.queue.consumerName(), false)
+
+	done := make(chan struct{})
+	go func() {
+		c.inFlight.Wait()
+		close(done)
+	}()
+
+	timeout := c.queue.options.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	case <-ctx.Done():
+	}
+
+	return err
+}
+
+// SubscribeHandler starts concurrency worker goroutines consuming from the
+// queue and calling handler for each delivery. Each handler invocation
+// shares a context that is canceled once the returned Consumer is closed.
+// The message is acked if handler returns nil, and nacked otherwise,
+// requeued according to QueueOptions.RequeueOnError. Like Subscribe, the
+// consumer is automatically restarted if the Exchange's connection is lost
+// and reconnected.
+func (q *Queue) SubscribeHandler(concurrency int, handler func(ctx context.Context, m *Message) error) (*Consumer, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	messages := make(chan *Message)
+	if err := q.Subscribe(messages); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var inFlight sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for {
+				// Select on ctx.Done() too so that once Close cancels it,
+				// workers return instead of blocking forever on messages,
+				// which redeclare() keeps feeding across reconnects and so
+				// is never closed. A delivery that is ready at the same
+				// moment may still be picked up and handled.
+				select {
+				case <-ctx.Done():
+					return
+				case m, ok := <-messages:
+					if !ok {
+						return
+					}
+
+					inFlight.Add(1)
+					if err := handler(ctx, m); err != nil {
+						m.Nack(q.options.RequeueOnError)
+					} else {
+						m.Ack()
+					}
+					inFlight.Done()
+				}
+			}
+		}()
+	}
+
+	return &Consumer{queue: q, cancel: cancel, inFlight: &inFlight}, nil
+}
+
+// consume starts the amqp consumer and funnels deliveries into messages. It's
+// called from Subscribe, and again for each active consumer after a
+// reconnect.
+func (q *Queue) consume(messages chan<- *Message) error {
+	dd, err := q.exchange.channelRef().Consume(
 		q.name,           // queue
 		q.consumerName(), // consumer name
 		false,            // autoAck
@@ -231,27 +1010,21 @@ func (q *Queue) Subscribe(messages chan<- *Message) error {
 	}
 
 	go func() {
-		open := true
-		for open {
-			select {
-			case d, ok := <-dd:
-				if !ok {
-					q.exchange.onDisconnect()
-					open = false
-					break
-				}
-
-				m := &Message{
-					Acknowledger: &acknowledger{
-						Acknowledger: d.Acknowledger,
-						deliveryTag:  d.DeliveryTag,
-					},
-					Headers: d.Headers,
-					Body:    d.Body,
-				}
-
-				messages <- m
+		for d := range dd {
+			m := &Message{
+				Acknowledger: &acknowledger{
+					Acknowledger: d.Acknowledger,
+					deliveryTag:  d.DeliveryTag,
+					queue:        q,
+					headers:      d.Headers,
+					body:         d.Body,
+					contentType:  d.ContentType,
+				},
+				Headers: d.Headers,
+				Body:    d.Body,
 			}
+
+			messages <- m
 		}
 	}()
 
@@ -260,11 +1033,11 @@ func (q *Queue) Subscribe(messages chan<- *Message) error {
 
 // Close closes the exchange.
 func (q *Queue) Close() error {
-	if err := q.exchange.channel.Cancel(q.consumerName(), false); err != nil {
+	if err := q.exchange.channelRef().Cancel(q.consumerName(), false); err != nil {
 		return err
 	}
 
-	ch := q.exchange.channel.NotifyClose(make(chan *amqp.Error))
+	ch := q.exchange.channelRef().NotifyClose(make(chan *amqp.Error))
 	q.exchange.Close()
 
 	// Wait for the deliveries to drain.
@@ -277,7 +1050,7 @@ func (q *Queue) Close() error {
 
 // bind binds the queue. This is called automatically when Subscribe is called.
 func (q *Queue) bind() error {
-	return q.exchange.channel.QueueBind(
+	return q.exchange.channelRef().QueueBind(
 		q.name,          // name
 		q.routingKey,    // key
 		q.exchange.Name, // exchange
@@ -297,28 +1070,131 @@ type Message struct {
 	Body    []byte
 }
 
-// Acknowledger allows a message to be acked or nacked (rejected).
+// Acknowledger allows a message to be acked, nacked (rejected), or retried
+// through the queue's dead-letter/retry-backoff configuration.
 type Acknowledger interface {
 	Ack() error
 	Nack(requeue bool) error
+
+	// Retry republishes the message to the next retry-backoff bucket, or
+	// nacks it to the dead-letter exchange once QueueOptions.MaxRetries has
+	// been exceeded. It behaves like Nack(false) if the queue has no
+	// RetryBackoff configured.
+	Retry() error
 }
 
 // acknowledger wraps an amqp.Acknowledger to implement the Acknowledger interface.
 type acknowledger struct {
 	amqp.Acknowledger
 	deliveryTag uint64
+
+	queue       *Queue
+	headers     amqp.Table
+	body        []byte
+	contentType string
+
+	mu    sync.Mutex
+	acked bool
+}
+
+// settle records that the delivery is being acked, nacked, or retried,
+// returning ErrAlreadyAcked if that already happened. It guards against
+// double-settling a delivery tag — e.g. a SubscribeHandler/SubscribeTyped
+// handler calling Retry and then returning nil, which would otherwise make
+// the wrapper Ack the same tag again and get a channel exception from the
+// broker.
+func (d *acknowledger) settle() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.acked {
+		return ErrAlreadyAcked
+	}
+	d.acked = true
+	return nil
 }
 
 // Ack implements Acknowledger Ack.
 func (d *acknowledger) Ack() error {
+	if err := d.settle(); err != nil {
+		return err
+	}
 	return d.Acknowledger.Ack(d.deliveryTag, false)
 }
 
 // Nack implements Acknowledger Nack.
 func (d *acknowledger) Nack(requeue bool) error {
+	if err := d.settle(); err != nil {
+		return err
+	}
 	return d.Acknowledger.Nack(d.deliveryTag, false, requeue)
 }
 
+// Retry implements Acknowledger Retry.
+func (d *acknowledger) Retry() error {
+	if err := d.settle(); err != nil {
+		return err
+	}
+
+	q := d.queue
+	if q == nil || len(q.options.RetryBackoff) == 0 {
+		return d.Acknowledger.Nack(d.deliveryTag, false, false)
+	}
+
+	count := d.retryCount() + 1
+	if count > q.options.MaxRetries {
+		return d.Acknowledger.Nack(d.deliveryTag, false, false)
+	}
+
+	bucket := count - 1
+	if bucket >= len(q.options.RetryBackoff) {
+		bucket = len(q.options.RetryBackoff) - 1
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.headers {
+		headers[k] = v
+	}
+	headers["x-retry-count"] = int32(count)
+
+	// Goes through doPublish (not a raw channelRef().Publish) so that, when
+	// Confirms is enabled, this delivery tag is accounted for in the same
+	// sequence as every other publish on the channel.
+	err := q.exchange.doPublish(
+		q.exchange.channelRef(),
+		"",                       // default exchange: route directly to the named queue
+		q.retryQueueName(bucket), // routing key
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.contentType,
+			Body:         d.body,
+			DeliveryMode: amqp.Persistent,
+		},
+		false, // mandatory
+		false, // immediate
+		nil,   // waiter: fire-and-forget, Retry doesn't wait on the ack
+	)
+	if err != nil {
+		return err
+	}
+
+	return d.Acknowledger.Ack(d.deliveryTag, false)
+}
+
+// retryCount returns the x-retry-count header value, or 0 if unset.
+func (d *acknowledger) retryCount() int {
+	switch n := d.headers["x-retry-count"].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return int(n)
+	default:
+		return 0
+	}
+}
+
 // Acknowledgement specifieds an acknowledgement type.
 type Acknowledgement int
 
@@ -379,3 +1255,9 @@ func (a *NullAcknowledger) Nack(requeue bool) error {
 	}
 	return nil
 }
+
+// Retry behaves like Nack(false); NullAcknowledger has no queue to retry or
+// dead-letter against.
+func (a *NullAcknowledger) Retry() error {
+	return a.Nack(false)
+}
@@ -1,7 +1,9 @@
 package amqp
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/streadway/amqp"
 )
@@ -11,3 +13,151 @@ func TestErrorsAreEqual(t *testing.T) {
 		t.Fatalf("Expected errors to be equal but: %v != %v", ErrClosed, amqp.ErrClosed)
 	}
 }
+
+// TestExchangeAllocateTagConcurrent guards against the tag sequence
+// desyncing from the broker's under concurrent publishers: every call must
+// observe a unique, monotonically assigned tag, and the pending map must end
+// up with exactly one entry per waiter.
+func TestExchangeAllocateTagConcurrent(t *testing.T) {
+	e := &Exchange{pending: make(map[uint64]chan confirmResult)}
+
+	const n = 100
+	tags := make([]uint64, n)
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			e.confirmsMu.Lock()
+			tags[i] = e.allocateTag(make(chan confirmResult, 1))
+			e.confirmsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool, n)
+	for _, tag := range tags {
+		if tag == 0 {
+			t.Fatalf("tag was never assigned")
+		}
+		if seen[tag] {
+			t.Fatalf("tag %d assigned more than once", tag)
+		}
+		seen[tag] = true
+	}
+
+	if e.nextTag != n {
+		t.Fatalf("expected nextTag == %d, got %d", n, e.nextTag)
+	}
+	if len(e.pending) != n {
+		t.Fatalf("expected %d pending waiters, got %d", n, len(e.pending))
+	}
+}
+
+func TestExchangeAllocateTagWithoutWaiter(t *testing.T) {
+	e := &Exchange{pending: make(map[uint64]chan confirmResult)}
+
+	tag := e.allocateTag(nil)
+	if tag != 1 {
+		t.Fatalf("expected tag 1, got %d", tag)
+	}
+	if len(e.pending) != 0 {
+		t.Fatalf("expected no pending waiters for a nil waiter, got %d", len(e.pending))
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	if got := nextInterval(time.Second, 0); got != 2*time.Second {
+		t.Fatalf("expected 2s, got %s", got)
+	}
+
+	if got := nextInterval(30*time.Second, 45*time.Second); got != 45*time.Second {
+		t.Fatalf("expected doubling to cap at max, got %s", got)
+	}
+}
+
+// fakeAcker is a bare-bones amqp.Acknowledger test double that records every
+// Ack/Nack it receives, so tests can assert the broker only ever sees one
+// settlement per delivery tag.
+type fakeAcker struct {
+	acks  []uint64
+	nacks []uint64
+}
+
+func (f *fakeAcker) Ack(tag uint64, multiple bool) error {
+	f.acks = append(f.acks, tag)
+	return nil
+}
+
+func (f *fakeAcker) Nack(tag uint64, multiple, requeue bool) error {
+	f.nacks = append(f.nacks, tag)
+	return nil
+}
+
+func (f *fakeAcker) Reject(tag uint64, requeue bool) error {
+	return nil
+}
+
+// TestAcknowledgerDoubleAck guards against a second Ack/Nack/Retry on an
+// already-settled delivery reaching the broker: RabbitMQ treats a double-ack
+// as a channel exception, which would take down every Queue sharing that
+// Exchange's channel.
+func TestAcknowledgerDoubleAck(t *testing.T) {
+	fake := &fakeAcker{}
+	d := &acknowledger{Acknowledger: fake, deliveryTag: 1}
+
+	if err := d.Ack(); err != nil {
+		t.Fatalf("unexpected error on first Ack: %v", err)
+	}
+	if err := d.Ack(); err != ErrAlreadyAcked {
+		t.Fatalf("expected ErrAlreadyAcked on second Ack, got %v", err)
+	}
+	if err := d.Nack(false); err != ErrAlreadyAcked {
+		t.Fatalf("expected ErrAlreadyAcked on Nack after Ack, got %v", err)
+	}
+	if len(fake.acks) != 1 || len(fake.nacks) != 0 {
+		t.Fatalf("expected exactly one Ack and no Nacks to reach the broker, got acks=%v nacks=%v", fake.acks, fake.nacks)
+	}
+}
+
+// TestAcknowledgerRetryThenAckIsNoop covers the documented dead-letter/retry
+// pattern of a SubscribeHandler/SubscribeTyped handler calling m.Retry() and
+// then returning nil: the wrapper's subsequent auto-Ack must be a no-op
+// rather than a second settlement of the same delivery tag.
+func TestAcknowledgerRetryThenAckIsNoop(t *testing.T) {
+	fake := &fakeAcker{}
+	d := &acknowledger{Acknowledger: fake, deliveryTag: 1}
+
+	if err := d.Retry(); err != nil {
+		t.Fatalf("unexpected error from Retry: %v", err)
+	}
+	if err := d.Ack(); err != ErrAlreadyAcked {
+		t.Fatalf("expected ErrAlreadyAcked from Ack after Retry, got %v", err)
+	}
+	if len(fake.nacks) != 1 {
+		t.Fatalf("expected Retry with no queue/backoff to fall back to a single Nack, got %v", fake.nacks)
+	}
+	if len(fake.acks) != 0 {
+		t.Fatalf("expected no Ack calls, got %v", fake.acks)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if got := jitter(time.Second, 0); got != time.Second {
+		t.Fatalf("expected no jitter with fraction 0, got %s", got)
+	}
+
+	interval := 10 * time.Second
+	fraction := 0.5
+	min := interval - time.Duration(float64(interval)*fraction)
+	max := interval + time.Duration(float64(interval)*fraction)
+
+	for i := 0; i < 100; i++ {
+		got := jitter(interval, fraction)
+		if got < min || got > max {
+			t.Fatalf("jitter %s out of expected range [%s, %s]", got, min, max)
+		}
+	}
+}
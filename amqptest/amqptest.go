@@ -21,11 +21,10 @@ func Publish(t *testing.T, route, message string) {
 
 func newTestExchange(t *testing.T) *amqp.Exchange {
 	e, err := amqp.NewExchange("", &amqp.ExchangeOptions{
-		Name:         "hutch",
-		Type:         "topic",
-		Durable:      true,
-		AutoDelete:   false,
-		OnDisconnect: func() bool { return true },
+		Name:       "hutch",
+		Type:       "topic",
+		Durable:    true,
+		AutoDelete: false,
 	})
 	if err != nil {
 		t.Fatal(err)
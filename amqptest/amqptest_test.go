@@ -1,11 +1,13 @@
 package amqptest
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"code.google.com/p/go-uuid/uuid"
 	"github.com/remind101/amqp"
 )
 
@@ -56,3 +58,92 @@ func TestPrefetchCount(t *testing.T) {
 	fmt.Printf("queue size: %d\n", s.Messages)
 	time.Sleep(30 * time.Second)
 }
+
+func newConfirmExchange(t *testing.T) *amqp.Exchange {
+	e, err := amqp.NewExchange("", &amqp.ExchangeOptions{
+		Name:       "hutch",
+		Type:       "topic",
+		Durable:    true,
+		AutoDelete: false,
+		Confirms:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// TestPublishWithConfirm exercises the confirm-mode publish path end to end
+// against a real broker, catching a regression where the locally tracked
+// delivery tag desyncs from the broker's (PublishWithConfirm would then hang
+// until ctx is done instead of returning the ack).
+func TestPublishWithConfirm(t *testing.T) {
+	e := newConfirmExchange(t)
+
+	key := "amqptest.confirms"
+	q, err := amqp.NewQueue("amqptest.confirm.queue", e, &amqp.QueueOptions{
+		Durable:    true,
+		AutoDelete: true,
+		RoutingKey: key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := e.PublishWithConfirm(ctx, key, "hello", uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Ack {
+		t.Fatalf("expected broker to ack the publish")
+	}
+}
+
+// TestPublishWithConfirmConcurrent publishes from many goroutines at once so
+// that, if tag allocation and ch.Publish were ever reordered relative to
+// each other, confirmations would resolve the wrong caller's waiter (or a
+// waiter would never be found and the call would hang until ctx is done).
+func TestPublishWithConfirmConcurrent(t *testing.T) {
+	e := newConfirmExchange(t)
+
+	key := "amqptest.confirms.concurrent"
+	q, err := amqp.NewQueue("amqptest.confirm.concurrent.queue", e, &amqp.QueueOptions{
+		Durable:    true,
+		AutoDelete: true,
+		RoutingKey: key,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	const n = 20
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			c, err := e.PublishWithConfirm(ctx, key, fmt.Sprintf("%d", i), uuid.New())
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !c.Ack {
+				errs <- fmt.Errorf("message %d: expected broker to ack the publish", i)
+				return
+			}
+			errs <- nil
+		}(i)
+	}
+
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package amqp
+
+import "encoding/json"
+
+// Codec marshals and unmarshals message bodies for Exchange.PublishValue and
+// Queue.SubscribeTyped, letting callers move off ad-hoc strings and raw
+// []byte bodies.
+type Codec interface {
+	// Marshal encodes v into a wire body, returning the content type it
+	// should be published with.
+	Marshal(v interface{}) (body []byte, contentType string, err error)
+
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// DefaultCodec is the Codec used when ExchangeOptions.Codec is nil.
+var DefaultCodec Codec = JSONCodec{}
+
+// JSONCodec marshals and unmarshals message bodies as JSON.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v interface{}) ([]byte, string, error) {
+	b, err := json.Marshal(v)
+	return b, "application/json", err
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,17 @@
+package amqp
+
+import "github.com/vmihailenco/msgpack"
+
+// MsgPackCodec marshals and unmarshals message bodies as MessagePack.
+type MsgPackCodec struct{}
+
+// Marshal implements Codec.
+func (MsgPackCodec) Marshal(v interface{}) ([]byte, string, error) {
+	b, err := msgpack.Marshal(v)
+	return b, "application/x-msgpack", err
+}
+
+// Unmarshal implements Codec.
+func (MsgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
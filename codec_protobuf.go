@@ -0,0 +1,32 @@
+package amqp
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ProtobufCodec marshals and unmarshals message bodies as protocol buffers.
+// Values passed to Marshal and Unmarshal must implement proto.Message.
+type ProtobufCodec struct{}
+
+// Marshal implements Codec.
+func (ProtobufCodec) Marshal(v interface{}) ([]byte, string, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, "", fmt.Errorf("amqp: %T does not implement proto.Message", v)
+	}
+
+	b, err := proto.Marshal(m)
+	return b, "application/x-protobuf", err
+}
+
+// Unmarshal implements Codec.
+func (ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("amqp: %T does not implement proto.Message", v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
@@ -0,0 +1,78 @@
+package amqp
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Dialer configures the low-level connection to the broker: TLS, SASL
+// mechanism, heartbeat, locale, frame/channel limits, custom client
+// properties and the transport used to dial. Set ExchangeOptions.Dialer to
+// use it; if nil, amqp.Dial's defaults apply.
+type Dialer struct {
+	// TLSClientConfig enables TLS (e.g. for amqps:// URLs) using the given
+	// client certificate/CA configuration.
+	TLSClientConfig *tls.Config
+
+	// SASL selects the authentication mechanism(s) to offer, e.g.
+	// []amqp.Authentication{&amqp.PlainAuth{...}} or
+	// []amqp.Authentication{&amqp.ExternalAuth{}}. If unset, PLAIN
+	// credentials parsed from the URL are used.
+	SASL []amqp.Authentication
+
+	// Heartbeat is the negotiated connection heartbeat interval.
+	Heartbeat time.Duration
+
+	// Locale is the locale offered in the connection handshake.
+	Locale string
+
+	// ChannelMax caps the number of channels that may be opened on the
+	// connection.
+	ChannelMax int
+
+	// FrameSize caps the size of frames sent to the broker.
+	FrameSize int
+
+	// Properties are custom client properties sent during the connection
+	// handshake.
+	Properties amqp.Table
+
+	// Dial, if set, is used to make the underlying network connection,
+	// allowing a custom dial timeout or transport.
+	Dial func(network, addr string) (net.Conn, error)
+}
+
+// defaultHeartbeat and defaultLocale match the unexported defaults
+// amqp.Dial uses, so that setting a Dialer only for e.g. TLS doesn't
+// silently change these from what amqp.Dial would have negotiated.
+const (
+	defaultHeartbeat = 10 * time.Second
+	defaultLocale    = "en_US"
+)
+
+// config builds the amqp.Config used with amqp.DialConfig.
+func (d *Dialer) config() amqp.Config {
+	heartbeat := d.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+
+	locale := d.Locale
+	if locale == "" {
+		locale = defaultLocale
+	}
+
+	return amqp.Config{
+		TLSClientConfig: d.TLSClientConfig,
+		SASL:            d.SASL,
+		Heartbeat:       heartbeat,
+		Locale:          locale,
+		ChannelMax:      d.ChannelMax,
+		FrameSize:       d.FrameSize,
+		Properties:      d.Properties,
+		Dial:            d.Dial,
+	}
+}